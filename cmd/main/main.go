@@ -1,14 +1,16 @@
 package main
 
 import (
-	"crypto/rand"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
+	"go-echo-sandbox/internal/chat"
 	"go-echo-sandbox/internal/db"
 	"go-echo-sandbox/internal/game"
+	"go-echo-sandbox/internal/twitch"
 	"go-echo-sandbox/ui"
-	"io"
 	"log"
 	"net/http"
 	"os"
@@ -17,22 +19,27 @@ import (
 
 	"github.com/gorilla/sessions"
 	"github.com/joho/godotenv"
+	"github.com/labstack/echo-contrib/echoprometheus"
 	"github.com/labstack/echo-contrib/session"
-	"github.com/nrednav/cuid2"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/twitch"
+	twitchoauth "golang.org/x/oauth2/twitch"
 	"golang.org/x/time/rate"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
-func GenerateSecureToken(length int) string {
-	b := make([]byte, length)
-	if _, err := rand.Read(b); err != nil {
-		return ""
-	}
-	return hex.EncodeToString(b)
+// authStateTTL bounds how long an OAuth state value is valid for, so a state
+// minted for a login that never completes can't be replayed indefinitely.
+const authStateTTL = 5 * time.Minute
+
+// signAuthState binds an OAuth state to the session that requested login,
+// so a state value lifted from one user's callback can't be replayed
+// against another session.
+func signAuthState(sessionID string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("AUTH_SECRET")))
+	mac.Write([]byte(sessionID))
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
 type OAuthConfig struct {
@@ -46,8 +53,15 @@ func TwitchConfig() oauth2.Config {
 		RedirectURL:  fmt.Sprintf("%s/api/auth/callback", os.Getenv("SITE_URL")),
 		ClientID:     os.Getenv("TWITCH_CLIENT_ID"),
 		ClientSecret: os.Getenv("TWITCH_CLIENT_SECRET"),
-		Scopes:       []string{"user:read:email"},
-		Endpoint:     twitch.Endpoint,
+		Scopes: []string{
+			"user:read:email",
+			"chat:read",
+			"chat:edit",
+			"channel:read:redemptions",
+			"channel:read:subscriptions",
+			"moderator:read:followers",
+		},
+		Endpoint: twitchoauth.Endpoint,
 	}
 	return AppOAuthConfig.TwitchLoginConfig
 }
@@ -64,17 +78,29 @@ func main() {
 	e := echo.New()
 	g := game.New(rdb)
 
+	twitchClient := twitch.NewClient(TwitchConfig(), rdb)
+
+	twitchEvents := make(chan twitch.Notification, 32)
+	go g.ConsumeTwitchEvents(twitchEvents)
+
+	isMetricsPath := func(c echo.Context) bool {
+		return strings.Contains(c.Path(), "metrics")
+	}
+
 	// e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 
-	// e.Use(echoprometheus.NewMiddleware("pixelbattle")) // adds middleware to gather metrics
+	e.Use(echoprometheus.NewMiddleware("pixelbattle")) // adds middleware to gather metrics
 	e.Use(middleware.BodyLimit("2M"))
-	e.Use(middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(rate.Limit(20))))
-	e.Use(session.Middleware(sessions.NewCookieStore([]byte(os.Getenv("AUTH_SECRET")))))
+	e.Use(middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Skipper: isMetricsPath,
+		Store:   middleware.NewRateLimiterMemoryStore(rate.Limit(20)),
+	}))
+	e.Use(session.Middleware(db.NewSessionStore(rdb, []byte(os.Getenv("AUTH_SECRET")))))
 	e.Use(middleware.GzipWithConfig(middleware.GzipConfig{
 		Level: 5,
 		Skipper: func(c echo.Context) bool {
-			return strings.Contains(c.Path(), "ws") // Change "metrics" for your own path
+			return strings.Contains(c.Path(), "ws") || isMetricsPath(c)
 		},
 	}))
 
@@ -83,7 +109,9 @@ func main() {
 
 	e.GET("/ws", g.WsHandler)
 
-	TwitchConfig()
+	e.GET("/metrics", echoprometheus.NewHandler(), middleware.BasicAuth(func(username, password string, c echo.Context) (bool, error) {
+		return username == os.Getenv("METRICS_USERNAME") && password == os.Getenv("METRICS_PASSWORD"), nil
+	}))
 
 	e.GET("/", func(c echo.Context) error {
 		sess, err := session.Get("session", c)
@@ -110,7 +138,7 @@ func main() {
 
 		user_id := sess.Values["user_id"].(string)
 
-		user_data := TwitchUser{}
+		user_data := twitch.User{}
 
 		err = rdb.Users.Get(c.Request().Context(), user_id).Scan(&user_data)
 		if err != nil {
@@ -129,10 +157,6 @@ func main() {
 	})
 
 	e.GET("/api/auth/login", func(c echo.Context) error {
-		state_key := cuid2.Generate()
-		state_value := GenerateSecureToken(8)
-
-		// generate session for user
 		sess, err := session.Get("session", c)
 		if err != nil {
 			return (err)
@@ -144,20 +168,26 @@ func main() {
 			HttpOnly: true,
 		}
 
-		sess.Values["twitch_auth_state"] = state_key
+		if sess.ID == "" {
+			sess.ID = db.NewSessionID()
+		}
+
 		if err := sess.Save(c.Request(), c.Response()); err != nil {
 			return (err)
 		}
 
-		// save state to kv store by session id
-		err = rdb.Auth.Set(c.Request().Context(), state_key, state_value, 0).Err()
+		state := signAuthState(sess.ID)
+
+		// save state keyed by session ID, with a TTL so it can't be replayed
+		// once the login it was minted for has gone stale
+		err = rdb.Auth.Set(c.Request().Context(), "state:"+sess.ID, state, authStateTTL).Err()
 		if err != nil {
 			return (err)
 		}
 
 		response_type := oauth2.SetAuthURLParam("response_type", `code`)
 
-		url := AppOAuthConfig.TwitchLoginConfig.AuthCodeURL(state_value, response_type)
+		url := AppOAuthConfig.TwitchLoginConfig.AuthCodeURL(state, response_type)
 
 		return c.Redirect(http.StatusTemporaryRedirect, url)
 	})
@@ -170,24 +200,21 @@ func main() {
 			return (err)
 		}
 
-		id, ok := sess.Values["twitch_auth_state"].(string)
-		if !ok {
+		if sess.ID == "" {
 			return c.String(http.StatusUnauthorized, "Session ID Not Found")
 		}
 
-		delete(sess.Values, "twitch_auth_state")
-
-		saved_state, err := rdb.Auth.Get(c.Request().Context(), id).Result()
+		saved_state, err := rdb.Auth.Get(c.Request().Context(), "state:"+sess.ID).Result()
 		if err != nil {
-			return (err)
+			return c.String(http.StatusUnauthorized, "State Expired")
 		}
 
-		err = rdb.Auth.Del(c.Request().Context(), id).Err()
+		err = rdb.Auth.Del(c.Request().Context(), "state:"+sess.ID).Err()
 		if err != nil {
 			return (err)
 		}
 
-		if state != saved_state {
+		if !hmac.Equal([]byte(state), []byte(saved_state)) {
 			return c.String(http.StatusUnauthorized, "States don't Match!")
 		}
 
@@ -200,42 +227,46 @@ func main() {
 			return c.String(http.StatusUnauthorized, "Code-Token Exchange Failed")
 		}
 
-		req, err := http.NewRequest("GET", "https://api.twitch.tv/helix/users", nil)
-		if err != nil {
-			return c.String(http.StatusInternalServerError, "User Data Fetch Failed")
-		}
-
-		req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-		req.Header.Add("Client-id", twitchcon.ClientID)
-
-		res, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return c.String(res.StatusCode, "User Data Fetch Failed")
-		}
-
-		userDatabody, err := io.ReadAll(res.Body)
-		if err != nil {
-			return c.String(http.StatusInternalServerError, "JSON Parsing Failed")
-		}
-
-		users := TwitchUserDataResponse{}
-
-		err = json.Unmarshal(userDatabody, &users)
+		user, err := twitchClient.IdentifyAndSave(c.Request().Context(), token)
 		if err != nil {
-			return c.String(http.StatusInternalServerError, "JSON Parsing Failed")
-		}
-
-		if len(users.Data) == 0 {
 			return c.String(http.StatusInternalServerError, "User Data Fetch Failed")
 		}
 
-		user := users.Data[0]
-
 		err = rdb.Users.Set(c.Request().Context(), user.ID, user, 0).Err()
 		if err != nil {
 			return (err)
 		}
 
+		if user.ID == os.Getenv("TWITCH_BROADCASTER_ID") {
+			tokenFunc := func(ctx context.Context) (string, error) {
+				source, err := twitchClient.TokenSource(ctx, user.ID)
+				if err != nil {
+					return "", err
+				}
+				token, err := source.Token()
+				if err != nil {
+					return "", err
+				}
+				return token.AccessToken, nil
+			}
+
+			if g.MarkEventSubStarted() {
+				forceRefreshFunc := func(ctx context.Context) (string, error) {
+					token, err := twitchClient.ForceRefresh(ctx, user.ID)
+					if err != nil {
+						return "", err
+					}
+					return token.AccessToken, nil
+				}
+				go runBroadcasterEventSub(context.Background(), twitchEvents, twitchcon.ClientID, tokenFunc, forceRefreshFunc, user.ID)
+			}
+
+			if chatChannel := os.Getenv("TWITCH_CHAT_CHANNEL"); chatChannel != "" && g.MarkChatStarted() {
+				chatClient := chat.New(g, user.Login, tokenFunc, chatChannel)
+				go chatClient.Run(context.Background())
+			}
+		}
+
 		sess.Values["user_id"] = user.ID
 		if err := sess.Save(c.Request(), c.Response()); err != nil {
 			return (err)
@@ -269,28 +300,41 @@ func main() {
 	e.Logger.Fatal(e.Start(":3000"))
 }
 
-type TwitchUserDataResponse struct {
-	Data []TwitchUser `json:"data"`
-}
+// runBroadcasterEventSub opens the broadcaster's EventSub session and
+// subscribes to the channel-points, subscribe, and follow events that drive
+// canvas effects, forwarding every notification onto events. token and
+// forceRefresh are backed by the Helix client's persisted, auto-refreshing
+// token source rather than a single access token captured at login time.
+func runBroadcasterEventSub(ctx context.Context, events chan<- twitch.Notification, clientID string, token, forceRefresh twitch.TokenFunc, broadcasterID string) {
+	client, err := twitch.Connect(ctx, clientID, token, forceRefresh, events)
+	if err != nil {
+		log.Printf("twitch: eventsub connect failed: %v", err)
+		return
+	}
 
-type TwitchUser struct {
-	ID              string    `json:"id"`
-	Login           string    `json:"login"`
-	DisplayName     string    `json:"display_name"`
-	Type            string    `json:"type"`
-	BroadcasterType string    `json:"broadcaster_type"`
-	Description     string    `json:"description"`
-	ProfileImageURL string    `json:"profile_image_url"`
-	OfflineImageURL string    `json:"offline_image_url"`
-	ViewCount       int64     `json:"view_count"`
-	Email           string    `json:"email"`
-	CreatedAt       time.Time `json:"created_at"`
-}
+	// moderator_user_id is only required by channel.follow, but Helix
+	// accepts it alongside broadcaster_user_id for all three, so it's
+	// simplest to always send both rather than build a condition per type.
+	condition := map[string]string{
+		"broadcaster_user_id": broadcasterID,
+		"moderator_user_id":   broadcasterID,
+	}
+	subscriptions := []struct {
+		eventType string
+		version   string
+	}{
+		{"channel.channel_points_custom_reward_redemption.add", "1"},
+		{"channel.subscribe", "1"},
+		{"channel.follow", "2"},
+	}
 
-func (t TwitchUser) MarshalBinary() (data []byte, err error) {
-	return json.Marshal(t)
-}
+	for _, sub := range subscriptions {
+		if err := client.Subscribe(ctx, sub.eventType, sub.version, condition); err != nil {
+			log.Printf("twitch: subscribe to %s failed: %v", sub.eventType, err)
+		}
+	}
 
-func (t *TwitchUser) UnmarshalBinary(data []byte) error {
-	return json.Unmarshal(data, t)
+	if err := client.Run(ctx); err != nil {
+		log.Printf("twitch: eventsub session ended: %v", err)
+	}
 }
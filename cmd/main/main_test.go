@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestSignAuthStateIsDeterministic(t *testing.T) {
+	t.Setenv("AUTH_SECRET", "test-secret")
+
+	a := signAuthState("session-1")
+	b := signAuthState("session-1")
+
+	if a != b {
+		t.Fatalf("expected signAuthState to be deterministic, got %q and %q", a, b)
+	}
+}
+
+func TestSignAuthStateDiffersPerSession(t *testing.T) {
+	t.Setenv("AUTH_SECRET", "test-secret")
+
+	a := signAuthState("session-1")
+	b := signAuthState("session-2")
+
+	if a == b {
+		t.Fatal("expected different sessions to produce different state values")
+	}
+}
+
+func TestSignAuthStateDiffersPerSecret(t *testing.T) {
+	t.Setenv("AUTH_SECRET", "secret-a")
+	a := signAuthState("session-1")
+
+	t.Setenv("AUTH_SECRET", "secret-b")
+	b := signAuthState("session-1")
+
+	if a == b {
+		t.Fatal("expected different AUTH_SECRET values to produce different state values")
+	}
+}
@@ -0,0 +1,177 @@
+// Package chat connects to Twitch IRC as the broadcaster and forwards chat
+// commands that place pixels on the canvas.
+package chat
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-echo-sandbox/internal/game"
+)
+
+const (
+	ircAddr = "irc.chat.twitch.tv:6697"
+
+	defaultCooldown = 30 * time.Second
+	vipCooldown     = 10 * time.Second
+
+	minBackoff = time.Second
+	maxBackoff = 2 * time.Minute
+)
+
+// TokenFunc returns a valid access token for the broadcaster, refreshing it
+// if necessary. It's called on every (re)connect attempt.
+type TokenFunc func(ctx context.Context) (string, error)
+
+// Client joins the broadcaster's channel and parses chat commands like
+// "!pixel <x> <y> <#rrggbb>" into pixel placements.
+type Client struct {
+	nick    string
+	token   TokenFunc
+	channel string
+	game    *game.Game
+}
+
+func New(g *game.Game, nick string, token TokenFunc, channel string) *Client {
+	return &Client{
+		nick:    nick,
+		token:   token,
+		channel: strings.ToLower(strings.TrimPrefix(channel, "#")),
+		game:    g,
+	}
+}
+
+// Run connects and processes chat until ctx is cancelled, reconnecting with
+// exponential backoff whenever the connection drops.
+func (c *Client) Run(ctx context.Context) {
+	backoff := minBackoff
+
+	for ctx.Err() == nil {
+		connectedAt := time.Now()
+
+		if err := c.runOnce(ctx); err != nil {
+			log.Printf("chat: connection lost: %v", err)
+		}
+
+		if time.Since(connectedAt) > 30*time.Second {
+			backoff = minBackoff
+		} else {
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (c *Client) runOnce(ctx context.Context) error {
+	accessToken, err := c.token(ctx)
+	if err != nil {
+		return fmt.Errorf("chat: get token: %w", err)
+	}
+
+	conn, err := tls.Dial("tcp", ircAddr, &tls.Config{ServerName: "irc.chat.twitch.tv"})
+	if err != nil {
+		return fmt.Errorf("chat: dial: %w", err)
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	writeLine(conn, "CAP REQ :twitch.tv/tags twitch.tv/commands")
+	writeLine(conn, "PASS oauth:%s", accessToken)
+	writeLine(conn, "NICK %s", c.nick)
+	writeLine(conn, "JOIN #%s", c.channel)
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("chat: read: %w", err)
+		}
+
+		msg := parseMessage(strings.TrimRight(line, "\r\n"))
+		if msg == nil {
+			continue
+		}
+
+		switch msg.command {
+		case "PING":
+			writeLine(conn, "PONG :%s", strings.Join(msg.params, " "))
+		case "PRIVMSG":
+			c.handlePrivmsg(msg)
+		}
+	}
+}
+
+func (c *Client) handlePrivmsg(msg *message) {
+	if len(msg.params) < 2 {
+		return
+	}
+
+	x, y, color, ok := parsePixelCommand(msg.params[1])
+	if !ok {
+		return
+	}
+
+	userID := msg.tags["user-id"]
+	if userID == "" {
+		return
+	}
+
+	cooldown := defaultCooldown
+	if isSubOrVIP(msg.tags["badges"]) {
+		cooldown = vipCooldown
+	}
+
+	c.game.TryPlaceWithCooldown(userID, game.Pixel{X: x, Y: y, Color: color}, cooldown)
+}
+
+func isSubOrVIP(badges string) bool {
+	return strings.Contains(badges, "subscriber") || strings.Contains(badges, "vip") || strings.Contains(badges, "founder")
+}
+
+// parsePixelCommand matches "!pixel <x> <y> <#rrggbb>".
+func parsePixelCommand(text string) (x, y int, color string, ok bool) {
+	fields := strings.Fields(text)
+	if len(fields) != 4 || fields[0] != "!pixel" {
+		return 0, 0, "", false
+	}
+
+	x, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, "", false
+	}
+
+	y, err = strconv.Atoi(fields[2])
+	if err != nil {
+		return 0, 0, "", false
+	}
+
+	return x, y, fields[3], true
+}
+
+func writeLine(conn net.Conn, format string, args ...any) {
+	fmt.Fprintf(conn, format+"\r\n", args...)
+}
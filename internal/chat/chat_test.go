@@ -0,0 +1,45 @@
+package chat
+
+import "testing"
+
+func TestParsePixelCommand(t *testing.T) {
+	x, y, color, ok := parsePixelCommand("!pixel 10 20 #ff00ff")
+	if !ok {
+		t.Fatal("expected command to parse")
+	}
+	if x != 10 || y != 20 || color != "#ff00ff" {
+		t.Fatalf("unexpected parse result: x=%d y=%d color=%q", x, y, color)
+	}
+}
+
+func TestParsePixelCommandRejectsGarbage(t *testing.T) {
+	cases := []string{
+		"",
+		"!pixel",
+		"!pixel 1 2",
+		"!clear 1 2 #fff",
+		"!pixel one two #fff",
+	}
+
+	for _, text := range cases {
+		if _, _, _, ok := parsePixelCommand(text); ok {
+			t.Errorf("expected %q to be rejected", text)
+		}
+	}
+}
+
+func TestIsSubOrVIP(t *testing.T) {
+	cases := map[string]bool{
+		"":                      false,
+		"subscriber/12":         true,
+		"vip/1":                 true,
+		"founder/0":             true,
+		"moderator/1,partner/1": false,
+	}
+
+	for badges, want := range cases {
+		if got := isSubOrVIP(badges); got != want {
+			t.Errorf("isSubOrVIP(%q) = %v, want %v", badges, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package chat
+
+import "strings"
+
+// message is a parsed Twitch IRC line: optional @tags, optional :prefix,
+// a command, and space-separated params (the last of which may be a
+// ":"-prefixed trailing multi-word argument).
+type message struct {
+	tags    map[string]string
+	prefix  string
+	command string
+	params  []string
+}
+
+func parseMessage(line string) *message {
+	if line == "" {
+		return nil
+	}
+
+	msg := &message{tags: map[string]string{}}
+
+	if strings.HasPrefix(line, "@") {
+		end := strings.IndexByte(line, ' ')
+		if end == -1 {
+			return nil
+		}
+		msg.tags = parseTags(line[1:end])
+		line = line[end+1:]
+	}
+
+	if strings.HasPrefix(line, ":") {
+		end := strings.IndexByte(line, ' ')
+		if end == -1 {
+			return nil
+		}
+		msg.prefix = line[1:end]
+		line = line[end+1:]
+	}
+
+	if idx := strings.Index(line, " :"); idx != -1 {
+		msg.params = append(strings.Fields(line[:idx]), line[idx+2:])
+		line = ""
+	} else {
+		msg.params = strings.Fields(line)
+	}
+
+	if len(msg.params) == 0 {
+		return nil
+	}
+
+	msg.command = msg.params[0]
+	msg.params = msg.params[1:]
+
+	return msg
+}
+
+func parseTags(raw string) map[string]string {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(raw, ";") {
+		key, value, _ := strings.Cut(pair, "=")
+		tags[key] = value
+	}
+	return tags
+}
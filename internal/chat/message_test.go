@@ -0,0 +1,52 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMessagePing(t *testing.T) {
+	msg := parseMessage("PING :tmi.twitch.tv")
+	if msg == nil {
+		t.Fatal("expected a parsed message")
+	}
+	if msg.command != "PING" {
+		t.Fatalf("expected command PING, got %q", msg.command)
+	}
+	if want := []string{"tmi.twitch.tv"}; !reflect.DeepEqual(msg.params, want) {
+		t.Fatalf("unexpected params: %v", msg.params)
+	}
+}
+
+func TestParseMessagePrivmsgWithTags(t *testing.T) {
+	line := "@badges=subscriber/12;user-id=12345 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #broadcaster :!pixel 1 2 #ffffff"
+
+	msg := parseMessage(line)
+	if msg == nil {
+		t.Fatal("expected a parsed message")
+	}
+
+	if msg.command != "PRIVMSG" {
+		t.Fatalf("expected command PRIVMSG, got %q", msg.command)
+	}
+
+	if got := msg.tags["user-id"]; got != "12345" {
+		t.Fatalf("expected user-id tag 12345, got %q", got)
+	}
+
+	if want := []string{"#broadcaster", "!pixel 1 2 #ffffff"}; !reflect.DeepEqual(msg.params, want) {
+		t.Fatalf("unexpected params: %v", msg.params)
+	}
+}
+
+func TestParseMessageEmptyLine(t *testing.T) {
+	if msg := parseMessage(""); msg != nil {
+		t.Fatalf("expected nil for empty line, got %+v", msg)
+	}
+}
+
+func TestParseMessageNoCommand(t *testing.T) {
+	if msg := parseMessage("@user-id=1 :prefix"); msg != nil {
+		t.Fatalf("expected nil when there's no command, got %+v", msg)
+	}
+}
@@ -0,0 +1,35 @@
+// Package db wires up the Redis connections the rest of the app reads and
+// writes through.
+package db
+
+import (
+	"os"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Rdb groups the Redis clients used across the app. Users and Auth are kept
+// on separate logical DBs so a flush of one (e.g. expiring auth state) can't
+// accidentally take out the other.
+type Rdb struct {
+	Users *redis.Client
+	Auth  *redis.Client
+}
+
+func NewRdb() *Rdb {
+	addr := os.Getenv("REDIS_ADDR")
+	password := os.Getenv("REDIS_PASSWORD")
+
+	newClient := func(db int) *redis.Client {
+		return redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: password,
+			DB:       db,
+		})
+	}
+
+	return &Rdb{
+		Users: newClient(0),
+		Auth:  newClient(1),
+	}
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/redis/go-redis/v9"
+)
+
+const sessionKeyPrefix = "session:"
+
+// SessionStore is a gorilla/sessions.Store backed by Redis: sessions survive
+// restarts, and revoking one server-side (e.g. on ban) is a single Delete
+// rather than waiting out a client-held cookie.
+type SessionStore struct {
+	client  *redis.Client
+	codecs  []securecookie.Codec
+	options sessions.Options
+}
+
+// NewSessionStore builds a SessionStore on top of rdb.Auth, authenticating
+// cookies with keyPairs the same way gorilla/sessions.NewCookieStore does.
+func NewSessionStore(rdb *Rdb, keyPairs ...[]byte) *SessionStore {
+	return &SessionStore{
+		client: rdb.Auth,
+		codecs: securecookie.CodecsFromPairs(keyPairs...),
+		options: sessions.Options{
+			Path:   "/",
+			MaxAge: 86400 * 7,
+		},
+	}
+}
+
+func (s *SessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *SessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := s.options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var sessionID string
+	if err := securecookie.DecodeMulti(name, c.Value, &sessionID, s.codecs...); err != nil {
+		return session, nil
+	}
+
+	session.ID = sessionID
+	if err := s.load(r.Context(), session); err != nil {
+		return session, nil
+	}
+	session.IsNew = false
+
+	return session, nil
+}
+
+func (s *SessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if err := s.Delete(r.Context(), session.ID); err != nil {
+			return err
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = NewSessionID()
+	}
+
+	if err := s.store(r.Context(), session); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return fmt.Errorf("db: encode session cookie: %w", err)
+	}
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Delete removes a session from Redis, revoking it immediately regardless
+// of whether the client still holds its cookie.
+func (s *SessionStore) Delete(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	return s.client.Del(ctx, sessionKeyPrefix+sessionID).Err()
+}
+
+func (s *SessionStore) load(ctx context.Context, session *sessions.Session) error {
+	data, err := s.client.Get(ctx, sessionKeyPrefix+session.ID).Bytes()
+	if err != nil {
+		return err
+	}
+
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values)
+}
+
+func (s *SessionStore) store(ctx context.Context, session *sessions.Session) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(session.Values); err != nil {
+		return fmt.Errorf("db: encode session: %w", err)
+	}
+
+	ttl := time.Duration(session.Options.MaxAge) * time.Second
+	if err := s.client.Set(ctx, sessionKeyPrefix+session.ID, buf.Bytes(), ttl).Err(); err != nil {
+		return fmt.Errorf("db: save session: %w", err)
+	}
+
+	return nil
+}
+
+// NewSessionID generates a random, URL-safe session identifier.
+func NewSessionID() string {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err)
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+}
@@ -0,0 +1,204 @@
+// Package game implements the shared pixel canvas: placing pixels,
+// rate-limiting how often a user may place one, and fanning state out to
+// every connected websocket client.
+package game
+
+import (
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-echo-sandbox/internal/db"
+)
+
+const (
+	CanvasWidth  = 1000
+	CanvasHeight = 1000
+
+	PlacementCooldown = 30 * time.Second
+)
+
+// Pixel is a single placement on the canvas.
+type Pixel struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Color string `json:"color"`
+}
+
+// Game holds the canvas state, the set of connected websocket clients, and
+// the per-user cooldown used to rate-limit placements.
+type Game struct {
+	rdb *db.Rdb
+
+	mu     sync.RWMutex
+	canvas [CanvasHeight][CanvasWidth]string
+
+	clientsMu sync.RWMutex
+	clients   map[*client]struct{}
+
+	cooldownMu sync.Mutex
+	cooldown   map[string]time.Time
+
+	eventSubStarted atomic.Bool
+	chatStarted     atomic.Bool
+}
+
+func New(rdb *db.Rdb) *Game {
+	return &Game{
+		rdb:      rdb,
+		clients:  make(map[*client]struct{}),
+		cooldown: make(map[string]time.Time),
+	}
+}
+
+// MarkEventSubStarted reports whether this call is the first to claim the
+// broadcaster's EventSub session, so a re-login (session expiry, new
+// device, re-auth after a scope change) doesn't open a second, duplicate
+// subscription stream. Subsequent calls return false until the process
+// restarts.
+func (g *Game) MarkEventSubStarted() bool {
+	return g.eventSubStarted.CompareAndSwap(false, true)
+}
+
+// MarkChatStarted reports whether this call is the first to claim the
+// broadcaster's chat connection, so a re-login doesn't open a second IRC
+// connection alongside the first (duplicate !pixel processing, double
+// joins). Subsequent calls return false until the process restarts.
+func (g *Game) MarkChatStarted() bool {
+	return g.chatStarted.CompareAndSwap(false, true)
+}
+
+// TryPlace places a pixel on behalf of userID if their cooldown has elapsed,
+// reporting whether the placement was accepted.
+func (g *Game) TryPlace(userID string, p Pixel) bool {
+	return g.tryPlace(userID, p, PlacementCooldown)
+}
+
+// TryPlaceWithCooldown is TryPlace, but lets the caller substitute a
+// different cooldown duration for this placement (e.g. chat granting
+// subscribers/VIPs a shorter one) while still sharing the same per-user
+// cooldown state as every other placement source.
+func (g *Game) TryPlaceWithCooldown(userID string, p Pixel, cooldown time.Duration) bool {
+	return g.tryPlace(userID, p, cooldown)
+}
+
+func (g *Game) tryPlace(userID string, p Pixel, cooldown time.Duration) bool {
+	if !inBounds(p.X, p.Y) || !validColor(p.Color) {
+		return false
+	}
+
+	if !g.consumeCooldown(userID, cooldown) {
+		rateLimitRejectionsTotal.Inc()
+		return false
+	}
+
+	g.place(p)
+	recordPlacement(userID, p)
+	return true
+}
+
+// Place writes a pixel to the canvas and broadcasts it to every connected
+// client, bypassing the per-user cooldown. Callers that act on behalf of a
+// specific user should use TryPlace instead. Out-of-bounds coordinates and
+// malformed colors are silently ignored, since p comes straight from
+// untrusted reward redemption text.
+func (g *Game) Place(p Pixel) {
+	if !inBounds(p.X, p.Y) || !validColor(p.Color) {
+		return
+	}
+	g.place(p)
+}
+
+func (g *Game) place(p Pixel) {
+	g.mu.Lock()
+	g.canvas[p.Y][p.X] = p.Color
+	g.mu.Unlock()
+
+	g.broadcast(p)
+}
+
+// ClearArea resets every pixel inside the given rectangle (inclusive) to the
+// empty color and broadcasts each cleared pixel. The rectangle is clamped to
+// the canvas bounds first, since x0..x1/y0..y1 may come straight from
+// untrusted reward redemption text.
+func (g *Game) ClearArea(x0, y0, x1, y1 int) {
+	x0, x1 = clampRange(x0, x1, CanvasWidth)
+	y0, y1 = clampRange(y0, y1, CanvasHeight)
+
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			g.place(Pixel{X: x, Y: y, Color: ""})
+		}
+	}
+}
+
+func inBounds(x, y int) bool {
+	return x >= 0 && x < CanvasWidth && y >= 0 && y < CanvasHeight
+}
+
+// colorPattern matches the only color format the canvas accepts: a 6-digit
+// hex RGB triple. Rejecting anything else here, before a color ever reaches
+// the canvas or pixelsPlacedTotal, keeps that counter's cardinality bounded
+// against viewers submitting arbitrary strings as a "color".
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+func validColor(color string) bool {
+	return colorPattern.MatchString(color)
+}
+
+// clampRange orders a and b so the smaller comes first and clamps them into
+// [0, size), so a reversed or out-of-range rectangle still yields a safe
+// iteration range. A range lying wholly outside [0, size) comes back empty
+// as (0, -1).
+func clampRange(a, b, size int) (int, int) {
+	if a > b {
+		a, b = b, a
+	}
+	if b < 0 || a >= size {
+		return 0, -1
+	}
+	return clampInt(a, 0, size-1), clampInt(b, 0, size-1)
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// SkipCooldown clears any pending cooldown for userID, letting their next
+// placement through immediately.
+func (g *Game) SkipCooldown(userID string) {
+	g.cooldownMu.Lock()
+	defer g.cooldownMu.Unlock()
+	delete(g.cooldown, userID)
+}
+
+func (g *Game) consumeCooldown(userID string, cooldown time.Duration) bool {
+	g.cooldownMu.Lock()
+	defer g.cooldownMu.Unlock()
+
+	if until, ok := g.cooldown[userID]; ok && time.Now().Before(until) {
+		return false
+	}
+
+	g.cooldown[userID] = time.Now().Add(cooldown)
+	return true
+}
+
+func (g *Game) broadcast(p Pixel) {
+	g.clientsMu.RLock()
+	defer g.clientsMu.RUnlock()
+
+	for c := range g.clients {
+		select {
+		case c.send <- p:
+		default:
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package game
+
+import "testing"
+
+func TestPlaceIgnoresOutOfBounds(t *testing.T) {
+	g := New(nil)
+
+	g.Place(Pixel{X: CanvasWidth + 100, Y: -1, Color: "#ffffff"})
+
+	for _, row := range g.canvas {
+		for _, c := range row {
+			if c != "" {
+				t.Fatalf("expected canvas to remain empty, found %q", c)
+			}
+		}
+	}
+}
+
+func TestTryPlaceRejectsOutOfBounds(t *testing.T) {
+	g := New(nil)
+
+	if g.TryPlace("user-1", Pixel{X: -1, Y: 0, Color: "#ffffff"}) {
+		t.Fatal("expected out-of-bounds placement to be rejected")
+	}
+}
+
+func TestTryPlaceRejectsInvalidColor(t *testing.T) {
+	g := New(nil)
+
+	cases := []string{"", "red", "#fff", "#gggggg", "#1234567"}
+	for _, color := range cases {
+		if g.TryPlace("user-1", Pixel{X: 0, Y: 0, Color: color}) {
+			t.Errorf("expected color %q to be rejected", color)
+		}
+	}
+
+	if g.canvas[0][0] != "" {
+		t.Fatalf("expected canvas to remain untouched, got %q", g.canvas[0][0])
+	}
+}
+
+func TestClearAreaClampsToCanvas(t *testing.T) {
+	g := New(nil)
+	g.canvas[0][0] = "#ffffff"
+	g.canvas[CanvasHeight-1][CanvasWidth-1] = "#ffffff"
+
+	g.ClearArea(-100, -100, CanvasWidth+100, CanvasHeight+100)
+
+	if g.canvas[0][0] != "" || g.canvas[CanvasHeight-1][CanvasWidth-1] != "" {
+		t.Fatal("expected ClearArea to clamp to canvas bounds and clear it")
+	}
+}
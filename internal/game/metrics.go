@@ -0,0 +1,55 @@
+package game
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// regionTileSize is the edge length of the square tiles pixels_placed_by_region_total
+// buckets placements into, so the counter cardinality stays bounded on a
+// large canvas.
+const regionTileSize = 64
+
+var (
+	pixelsPlacedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pixelbattle",
+		Name:      "pixels_placed_total",
+		Help:      "Total pixels placed, labeled by the placing user and color.",
+	}, []string{"user_id", "color"})
+
+	pixelsPlacedByRegionTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pixelbattle",
+		Name:      "pixels_placed_by_region_total",
+		Help:      "Total pixels placed, bucketed into 64x64 tiles.",
+	}, []string{"tile_x", "tile_y"})
+
+	wsConnectionsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "pixelbattle",
+		Name:      "ws_connections_active",
+		Help:      "Number of currently open websocket connections.",
+	})
+
+	wsMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pixelbattle",
+		Name:      "ws_messages_total",
+		Help:      "Total websocket messages, labeled by direction (in or out).",
+	}, []string{"direction"})
+
+	rateLimitRejectionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "pixelbattle",
+		Name:      "rate_limit_rejections_total",
+		Help:      "Total pixel placements rejected by the per-user cooldown.",
+	})
+)
+
+// recordPlacement updates the per-user and per-region counters for a pixel
+// accepted on behalf of userID.
+func recordPlacement(userID string, p Pixel) {
+	pixelsPlacedTotal.WithLabelValues(userID, p.Color).Inc()
+
+	tileX := strconv.Itoa(p.X / regionTileSize)
+	tileY := strconv.Itoa(p.Y / regionTileSize)
+	pixelsPlacedByRegionTotal.WithLabelValues(tileX, tileY).Inc()
+}
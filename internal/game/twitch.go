@@ -0,0 +1,104 @@
+package game
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+
+	"go-echo-sandbox/internal/twitch"
+)
+
+// reward titles are matched as configured by the broadcaster in the Twitch
+// dashboard; these are the ones xakaxata ships with by convention.
+const (
+	rewardPlacePixel   = "Place Pixel"
+	rewardClearArea    = "Clear Area"
+	rewardCooldownSkip = "Cooldown Skip"
+)
+
+type rewardRedemptionEvent struct {
+	UserID    string `json:"user_id"`
+	UserInput string `json:"user_input"`
+	Reward    struct {
+		Title string `json:"title"`
+	} `json:"reward"`
+}
+
+// ConsumeTwitchEvents reads notifications off events until the channel is
+// closed, applying the effect associated with each channel-points
+// redemption to the canvas.
+func (g *Game) ConsumeTwitchEvents(events <-chan twitch.Notification) {
+	for n := range events {
+		if n.Subscription.Type != "channel.channel_points_custom_reward_redemption.add" {
+			continue
+		}
+
+		var event rewardRedemptionEvent
+		if err := json.Unmarshal(n.Event, &event); err != nil {
+			log.Printf("game: dropping unparsable redemption: %v", err)
+			continue
+		}
+
+		g.applyRewardRedemption(event)
+	}
+}
+
+func (g *Game) applyRewardRedemption(event rewardRedemptionEvent) {
+	switch event.Reward.Title {
+	case rewardPlacePixel:
+		p, ok := parsePixelInput(event.UserInput)
+		if !ok {
+			return
+		}
+		g.Place(p)
+	case rewardClearArea:
+		x0, y0, x1, y1, ok := parseAreaInput(event.UserInput)
+		if !ok {
+			return
+		}
+		g.ClearArea(x0, y0, x1, y1)
+	case rewardCooldownSkip:
+		g.SkipCooldown(event.UserID)
+	}
+}
+
+// parsePixelInput expects the redeemer's reward prompt input in "x,y,#rrggbb" form.
+func parsePixelInput(input string) (Pixel, bool) {
+	parts := strings.Split(strings.TrimSpace(input), ",")
+	if len(parts) != 3 {
+		return Pixel{}, false
+	}
+
+	x, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Pixel{}, false
+	}
+
+	y, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return Pixel{}, false
+	}
+
+	return Pixel{X: x, Y: y, Color: strings.TrimSpace(parts[2])}, true
+}
+
+// parseAreaInput expects the redeemer's reward prompt input in
+// "x0,y0,x1,y1" form.
+func parseAreaInput(input string) (x0, y0, x1, y1 int, ok bool) {
+	parts := strings.Split(strings.TrimSpace(input), ",")
+	if len(parts) != 4 {
+		return 0, 0, 0, 0, false
+	}
+
+	values := make([]int, 4)
+	for i, part := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return 0, 0, 0, 0, false
+		}
+		values[i] = v
+	}
+
+	return values[0], values[1], values[2], values[3], true
+}
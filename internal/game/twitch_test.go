@@ -0,0 +1,41 @@
+package game
+
+import "testing"
+
+func TestParsePixelInput(t *testing.T) {
+	p, ok := parsePixelInput(" 5 , 6 , #112233 ")
+	if !ok {
+		t.Fatal("expected input to parse")
+	}
+	if p.X != 5 || p.Y != 6 || p.Color != "#112233" {
+		t.Fatalf("unexpected pixel: %+v", p)
+	}
+}
+
+func TestParsePixelInputRejectsGarbage(t *testing.T) {
+	cases := []string{"", "1,2", "1,2,3,4", "a,b,#fff"}
+	for _, input := range cases {
+		if _, ok := parsePixelInput(input); ok {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestParseAreaInput(t *testing.T) {
+	x0, y0, x1, y1, ok := parseAreaInput("1, 2, 3, 4")
+	if !ok {
+		t.Fatal("expected input to parse")
+	}
+	if x0 != 1 || y0 != 2 || x1 != 3 || y1 != 4 {
+		t.Fatalf("unexpected area: %d,%d,%d,%d", x0, y0, x1, y1)
+	}
+}
+
+func TestParseAreaInputRejectsGarbage(t *testing.T) {
+	cases := []string{"", "1,2,3", "a,b,c,d"}
+	for _, input := range cases {
+		if _, _, _, _, ok := parseAreaInput(input); ok {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package game
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+type client struct {
+	userID string
+	conn   *websocket.Conn
+	send   chan Pixel
+}
+
+func (c *client) writePump() {
+	for p := range c.send {
+		if err := c.conn.WriteJSON(p); err != nil {
+			return
+		}
+		wsMessagesTotal.WithLabelValues("out").Inc()
+	}
+}
+
+// WsHandler upgrades the request to a websocket connection, streams every
+// placement to the client, and applies each placement the client sends back
+// (subject to their per-user cooldown).
+func (g *Game) WsHandler(c echo.Context) error {
+	var userID string
+	if sess, err := session.Get("session", c); err == nil {
+		if id, ok := sess.Values["user_id"].(string); ok {
+			userID = id
+		}
+	}
+
+	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return err
+	}
+
+	cl := &client{userID: userID, conn: conn, send: make(chan Pixel, 16)}
+
+	g.clientsMu.Lock()
+	g.clients[cl] = struct{}{}
+	g.clientsMu.Unlock()
+	wsConnectionsActive.Inc()
+
+	go cl.writePump()
+
+	defer func() {
+		g.clientsMu.Lock()
+		delete(g.clients, cl)
+		g.clientsMu.Unlock()
+		wsConnectionsActive.Dec()
+		close(cl.send)
+		conn.Close()
+	}()
+
+	for {
+		var p Pixel
+		if err := conn.ReadJSON(&p); err != nil {
+			return nil
+		}
+		wsMessagesTotal.WithLabelValues("in").Inc()
+
+		if cl.userID == "" {
+			continue
+		}
+
+		g.TryPlace(cl.userID, p)
+	}
+}
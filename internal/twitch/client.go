@@ -0,0 +1,308 @@
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go-echo-sandbox/internal/db"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrUnauthorized is returned when a Helix call comes back 401 even after a
+// refresh attempt, meaning the user needs to log in again.
+var ErrUnauthorized = errors.New("twitch: user token is no longer valid")
+
+// Client performs Helix API calls on behalf of individual users, persisting
+// their OAuth token in Redis and transparently refreshing it as needed.
+// Every long-lived Twitch integration (EventSub, chat, moderation) is built
+// on top of this.
+type Client struct {
+	oauthConfig oauth2.Config
+	rdb         *db.Rdb
+}
+
+func NewClient(oauthConfig oauth2.Config, rdb *db.Rdb) *Client {
+	return &Client{oauthConfig: oauthConfig, rdb: rdb}
+}
+
+func tokenKey(userID string) string {
+	return "token:" + userID
+}
+
+// SaveToken persists userID's OAuth token, overwriting whatever was stored
+// before.
+func (c *Client) SaveToken(ctx context.Context, userID string, token *oauth2.Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("twitch: marshal token: %w", err)
+	}
+
+	return c.rdb.Users.Set(ctx, tokenKey(userID), data, 0).Err()
+}
+
+func (c *Client) loadToken(ctx context.Context, userID string) (*oauth2.Token, error) {
+	data, err := c.rdb.Users.Get(ctx, tokenKey(userID)).Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("twitch: load token for %s: %w", userID, err)
+	}
+
+	var token oauth2.Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("twitch: unmarshal token for %s: %w", userID, err)
+	}
+
+	return &token, nil
+}
+
+// tokenSaver is the slice of Client that persistingTokenSource needs, kept
+// narrow so it can be exercised in tests without a real Redis behind it.
+type tokenSaver interface {
+	SaveToken(ctx context.Context, userID string, token *oauth2.Token) error
+}
+
+// persistingTokenSource wraps oauth2.ReuseTokenSource and writes the token
+// back to Redis whenever it refreshes, so the next call (or a different
+// process, like the EventSub subscriber) picks up the new access token
+// without the user re-authenticating.
+type persistingTokenSource struct {
+	ctx    context.Context
+	userID string
+	saver  tokenSaver
+	source oauth2.TokenSource
+	last   string
+}
+
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if token.AccessToken != s.last {
+		s.last = token.AccessToken
+		if err := s.saver.SaveToken(s.ctx, s.userID, token); err != nil {
+			return nil, err
+		}
+	}
+
+	return token, nil
+}
+
+// TokenSource returns a token source for userID that refreshes their stored
+// token via Twitch when it's expired.
+func (c *Client) TokenSource(ctx context.Context, userID string) (oauth2.TokenSource, error) {
+	token, err := c.loadToken(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &persistingTokenSource{
+		ctx:    ctx,
+		userID: userID,
+		saver:  c,
+		source: oauth2.ReuseTokenSource(token, c.oauthConfig.TokenSource(ctx, token)),
+	}, nil
+}
+
+// ForceRefresh refreshes userID's token unconditionally, even if the stored
+// token doesn't look expired yet. Use this after Helix itself has rejected
+// the cached token with a 401, which TokenSource's normal expiry check
+// wouldn't have caught.
+func (c *Client) ForceRefresh(ctx context.Context, userID string) (*oauth2.Token, error) {
+	token, err := c.loadToken(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := *token
+	expired.Expiry = time.Now().Add(-time.Minute)
+
+	fresh, err := c.oauthConfig.TokenSource(ctx, &expired).Token()
+	if err != nil {
+		return nil, fmt.Errorf("twitch: force refresh token for %s: %w", userID, err)
+	}
+
+	if err := c.SaveToken(ctx, userID, fresh); err != nil {
+		return nil, err
+	}
+
+	return fresh, nil
+}
+
+// IdentifyAndSave fetches the Helix profile for a freshly-exchanged token
+// (which has no known user ID yet) and persists the token under that
+// profile's ID, as done right after the OAuth callback.
+func (c *Client) IdentifyAndSave(ctx context.Context, token *oauth2.Token) (*User, error) {
+	user, err := c.getUserWithToken(ctx, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.SaveToken(ctx, user.ID, token); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (c *Client) getUserWithToken(ctx context.Context, accessToken string) (*User, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.twitch.tv/helix/users", nil)
+	if err != nil {
+		return nil, fmt.Errorf("twitch: build request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Client-Id", c.oauthConfig.ClientID)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("twitch: get user: %w", err)
+	}
+	defer res.Body.Close()
+
+	var body usersResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("twitch: decode user response: %w", err)
+	}
+
+	if len(body.Data) == 0 {
+		return nil, fmt.Errorf("twitch: no user data in response")
+	}
+
+	return &body.Data[0], nil
+}
+
+// helixRequest performs an authenticated Helix API call on behalf of
+// userID, refreshing their token first if it's expired. A 401 triggers one
+// forced refresh and retry before ErrUnauthorized is returned.
+func (c *Client) helixRequest(ctx context.Context, userID, method, rawURL string, query url.Values) (*http.Response, error) {
+	source, err := c.TokenSource(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("twitch: refresh token for %s: %w", userID, err)
+	}
+
+	if query != nil {
+		rawURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("twitch: build request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Client-Id", c.oauthConfig.ClientID)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("twitch: helix request: %w", err)
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		res.Body.Close()
+
+		fresh, err := c.ForceRefresh(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("twitch: refresh token for %s after 401: %w", userID, err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+fresh.AccessToken)
+		res, err = http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("twitch: helix request: %w", err)
+		}
+
+		if res.StatusCode == http.StatusUnauthorized {
+			res.Body.Close()
+			return nil, fmt.Errorf("twitch: helix request for %s: %w", userID, ErrUnauthorized)
+		}
+	}
+
+	return res, nil
+}
+
+// GetUser fetches userID's own Helix profile, authenticating with their
+// stored token.
+func (c *Client) GetUser(ctx context.Context, userID string) (*User, error) {
+	res, err := c.helixRequest(ctx, userID, http.MethodGet, "https://api.twitch.tv/helix/users", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var body usersResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("twitch: decode user response: %w", err)
+	}
+
+	if len(body.Data) == 0 {
+		return nil, fmt.Errorf("twitch: user %s not found", userID)
+	}
+
+	return &body.Data[0], nil
+}
+
+// GetUsersByLogin fetches Helix profiles for the given login names,
+// authenticating with userID's stored token.
+func (c *Client) GetUsersByLogin(ctx context.Context, userID string, logins ...string) ([]User, error) {
+	query := url.Values{}
+	for _, login := range logins {
+		query.Add("login", login)
+	}
+
+	res, err := c.helixRequest(ctx, userID, http.MethodGet, "https://api.twitch.tv/helix/users", query)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var body usersResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("twitch: decode users response: %w", err)
+	}
+
+	return body.Data, nil
+}
+
+// ValidateToken calls Twitch's /oauth2/validate endpoint, which Twitch
+// requires long-lived integrations to do at least once per hour.
+func (c *Client) ValidateToken(ctx context.Context, userID string) error {
+	source, err := c.TokenSource(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	token, err := source.Token()
+	if err != nil {
+		return fmt.Errorf("twitch: refresh token for %s: %w", userID, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://id.twitch.tv/oauth2/validate", nil)
+	if err != nil {
+		return fmt.Errorf("twitch: build validate request: %w", err)
+	}
+	req.Header.Set("Authorization", "OAuth "+token.AccessToken)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("twitch: validate token for %s: %w", userID, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("twitch: token for %s failed validation with status %d", userID, res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package twitch
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+type fakeTokenSource struct {
+	tokens []*oauth2.Token
+	i      int
+}
+
+func (f *fakeTokenSource) Token() (*oauth2.Token, error) {
+	token := f.tokens[f.i]
+	if f.i < len(f.tokens)-1 {
+		f.i++
+	}
+	return token, nil
+}
+
+type fakeTokenSaver struct {
+	saved []*oauth2.Token
+}
+
+func (f *fakeTokenSaver) SaveToken(ctx context.Context, userID string, token *oauth2.Token) error {
+	f.saved = append(f.saved, token)
+	return nil
+}
+
+func TestPersistingTokenSourceSavesOnlyWhenAccessTokenChanges(t *testing.T) {
+	saver := &fakeTokenSaver{}
+	source := &persistingTokenSource{
+		ctx:    context.Background(),
+		userID: "user-1",
+		saver:  saver,
+		source: &fakeTokenSource{tokens: []*oauth2.Token{
+			{AccessToken: "token-a"},
+			{AccessToken: "token-a"},
+			{AccessToken: "token-b"},
+			{AccessToken: "token-b"},
+		}},
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := source.Token(); err != nil {
+			t.Fatalf("Token() call %d: %v", i, err)
+		}
+	}
+
+	if len(saver.saved) != 2 {
+		t.Fatalf("expected SaveToken to be called exactly twice (once per distinct access token), got %d calls", len(saver.saved))
+	}
+
+	if saver.saved[0].AccessToken != "token-a" || saver.saved[1].AccessToken != "token-b" {
+		t.Fatalf("unexpected saved tokens: %+v", saver.saved)
+	}
+}
@@ -0,0 +1,243 @@
+// Package twitch talks to Twitch's Helix API and EventSub WebSocket feed on
+// behalf of the logged-in broadcaster.
+package twitch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+const eventSubWebSocketURL = "wss://eventsub.wss.twitch.tv/ws"
+
+// Notification is a single EventSub payload delivered over the session.
+type Notification struct {
+	Subscription Subscription    `json:"subscription"`
+	Event        json.RawMessage `json:"event"`
+}
+
+type Subscription struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Version string `json:"version"`
+}
+
+type welcomeMessage struct {
+	Metadata struct {
+		MessageType string `json:"message_type"`
+	} `json:"metadata"`
+	Payload struct {
+		Session struct {
+			ID           string `json:"id"`
+			ReconnectURL string `json:"reconnect_url"`
+		} `json:"session"`
+	} `json:"payload"`
+}
+
+type notificationMessage struct {
+	Metadata struct {
+		MessageType string `json:"message_type"`
+	} `json:"metadata"`
+	Payload Notification `json:"payload"`
+}
+
+// TokenFunc returns a valid access token for the broadcaster. forceRefresh
+// is the same shape but refreshes unconditionally, for use after Helix
+// itself has rejected the cached token with a 401.
+type TokenFunc func(ctx context.Context) (string, error)
+
+// EventSubClient maintains a single EventSub WebSocket session and forwards
+// notification payloads to the channel supplied at construction time.
+type EventSubClient struct {
+	clientID     string
+	token        TokenFunc
+	forceRefresh TokenFunc
+
+	events    chan<- Notification
+	conn      *websocket.Conn
+	sessionID string
+}
+
+// Connect dials the EventSub WebSocket endpoint and blocks until the
+// session_welcome message arrives, returning a client ready for Subscribe.
+func Connect(ctx context.Context, clientID string, token, forceRefresh TokenFunc, events chan<- Notification) (*EventSubClient, error) {
+	return dial(ctx, eventSubWebSocketURL, clientID, token, forceRefresh, events)
+}
+
+func dial(ctx context.Context, url, clientID string, token, forceRefresh TokenFunc, events chan<- Notification) (*EventSubClient, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("twitch: dial eventsub: %w", err)
+	}
+
+	c := &EventSubClient{
+		clientID:     clientID,
+		token:        token,
+		forceRefresh: forceRefresh,
+		events:       events,
+		conn:         conn,
+	}
+
+	if err := c.awaitWelcome(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *EventSubClient) awaitWelcome() error {
+	var msg welcomeMessage
+	if err := c.conn.ReadJSON(&msg); err != nil {
+		return fmt.Errorf("twitch: read session_welcome: %w", err)
+	}
+
+	if msg.Metadata.MessageType != "session_welcome" {
+		return fmt.Errorf("twitch: expected session_welcome, got %q", msg.Metadata.MessageType)
+	}
+
+	c.sessionID = msg.Payload.Session.ID
+	return nil
+}
+
+// Subscribe registers a subscription for the given event type against this
+// session, scoped by condition (e.g. {"broadcaster_user_id": "..."}). If
+// Helix rejects the request with a 401, the token is force-refreshed and
+// the subscription is retried once before giving up.
+func (c *EventSubClient) Subscribe(ctx context.Context, eventType, version string, condition map[string]string) error {
+	accessToken, err := c.token(ctx)
+	if err != nil {
+		return fmt.Errorf("twitch: get token: %w", err)
+	}
+
+	status, err := c.subscribeOnce(ctx, accessToken, eventType, version, condition)
+	if err != nil {
+		return err
+	}
+
+	if status == http.StatusUnauthorized {
+		accessToken, err = c.forceRefresh(ctx)
+		if err != nil {
+			return fmt.Errorf("twitch: refresh token after 401: %w", err)
+		}
+
+		status, err = c.subscribeOnce(ctx, accessToken, eventType, version, condition)
+		if err != nil {
+			return err
+		}
+	}
+
+	if status != http.StatusAccepted {
+		return fmt.Errorf("twitch: subscribe to %s failed with status %d", eventType, status)
+	}
+
+	return nil
+}
+
+func (c *EventSubClient) subscribeOnce(ctx context.Context, accessToken, eventType, version string, condition map[string]string) (int, error) {
+	body := map[string]any{
+		"type":      eventType,
+		"version":   version,
+		"condition": condition,
+		"transport": map[string]string{
+			"method":     "websocket",
+			"session_id": c.sessionID,
+		},
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, fmt.Errorf("twitch: marshal subscription: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.twitch.tv/helix/eventsub/subscriptions", bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("twitch: build subscription request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Client-Id", c.clientID)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("twitch: subscribe to %s: %w", eventType, err)
+	}
+	defer res.Body.Close()
+
+	return res.StatusCode, nil
+}
+
+// Run reads messages off the session until ctx is cancelled or the
+// connection is lost, forwarding notifications to the events channel and
+// transparently following session_reconnect hand-offs.
+func (c *EventSubClient) Run(ctx context.Context) error {
+	defer c.conn.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		_, raw, err := c.conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("twitch: read message: %w", err)
+		}
+
+		var meta struct {
+			Metadata struct {
+				MessageType string `json:"message_type"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			log.Printf("twitch: dropping unparsable message: %v", err)
+			continue
+		}
+
+		switch meta.Metadata.MessageType {
+		case "session_keepalive":
+			// no-op, the connection is alive as long as reads keep succeeding
+		case "notification":
+			var n notificationMessage
+			if err := json.Unmarshal(raw, &n); err != nil {
+				log.Printf("twitch: dropping unparsable notification: %v", err)
+				continue
+			}
+			c.events <- n.Payload
+		case "session_reconnect":
+			var r welcomeMessage
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return fmt.Errorf("twitch: parse session_reconnect: %w", err)
+			}
+			if err := c.reconnect(ctx, r.Payload.Session.ReconnectURL); err != nil {
+				return err
+			}
+		default:
+			log.Printf("twitch: ignoring message type %q", meta.Metadata.MessageType)
+		}
+	}
+}
+
+// reconnect dials the URL Twitch supplied in session_reconnect and swaps it
+// in once the new session has sent its own welcome, only then closing the
+// old connection as the EventSub protocol requires.
+func (c *EventSubClient) reconnect(ctx context.Context, url string) error {
+	next, err := dial(ctx, url, c.clientID, c.token, c.forceRefresh, c.events)
+	if err != nil {
+		return fmt.Errorf("twitch: reconnect: %w", err)
+	}
+
+	old := c.conn
+	c.conn = next.conn
+	c.sessionID = next.sessionID
+	old.Close()
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package twitch
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// User is a Helix user profile, as returned by GET /helix/users.
+type User struct {
+	ID              string    `json:"id"`
+	Login           string    `json:"login"`
+	DisplayName     string    `json:"display_name"`
+	Type            string    `json:"type"`
+	BroadcasterType string    `json:"broadcaster_type"`
+	Description     string    `json:"description"`
+	ProfileImageURL string    `json:"profile_image_url"`
+	OfflineImageURL string    `json:"offline_image_url"`
+	ViewCount       int64     `json:"view_count"`
+	Email           string    `json:"email"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (u User) MarshalBinary() (data []byte, err error) {
+	return json.Marshal(u)
+}
+
+func (u *User) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, u)
+}
+
+type usersResponse struct {
+	Data []User `json:"data"`
+}